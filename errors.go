@@ -0,0 +1,95 @@
+package iamport
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// errorKind Error.Is가 ErrUnauthorized 등의 sentinel과 비교할 때 쓰는 내부 분류
+type errorKind string
+
+const (
+	kindUnauthorized     errorKind = "unauthorized"
+	kindNotFound         errorKind = "not_found"
+	kindAlreadyCancelled errorKind = "already_cancelled"
+	kindAmountMismatch   errorKind = "amount_mismatch"
+)
+
+// Error 아임포트 API 호출이 실패했을 때 반환하는 구조화된 에러.
+// errors.Is/errors.As로 ErrUnauthorized, ErrNotFound, ErrAlreadyCancelled,
+// ErrAmountMismatch와 비교하거나 꺼내 쓸 수 있다.
+type Error struct {
+	// Op 실패한 Client 메서드 이름 (예: "CancelPaymentImpUID")
+	Op string
+	// UID 대상이 된 imp_uid/merchant_uid/customer_uid 등
+	UID string
+	// Code 아임포트 응답 envelope의 code 필드
+	Code int
+	// Message 아임포트 응답 envelope의 message 필드
+	Message string
+	// HTTPStatus 응답의 HTTP 상태 코드
+	HTTPStatus int
+
+	kind errorKind
+}
+
+func (e *Error) Error() string {
+	if e.UID != "" {
+		return fmt.Sprintf("iamport: %s(%s): %s", e.Op, e.UID, e.Message)
+	}
+	return fmt.Sprintf("iamport: %s: %s", e.Op, e.Message)
+}
+
+// Is e가 target과 같은 종류의 실패인지 비교한다. target은 ErrUnauthorized,
+// ErrNotFound, ErrAlreadyCancelled, ErrAmountMismatch 중 하나여야 한다.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || t.kind == "" {
+		return false
+	}
+	return e.kind == t.kind
+}
+
+// ErrUnauthorized APIKey/APISecret 또는 AccessToken이 유효하지 않을 때의 에러
+var ErrUnauthorized = &Error{Message: "unauthorized", kind: kindUnauthorized}
+
+// ErrNotFound imp_uid/merchant_uid/customer_uid 등으로 대상을 찾을 수 없을 때의 에러
+var ErrNotFound = &Error{Message: "not found", kind: kindNotFound}
+
+// ErrAlreadyCancelled 이미 취소된 결제를 다시 취소하려 할 때의 에러
+var ErrAlreadyCancelled = &Error{Message: "already cancelled", kind: kindAlreadyCancelled}
+
+// ErrAmountMismatch 취소 금액 등이 실제 결제 금액과 맞지 않을 때의 에러
+var ErrAmountMismatch = &Error{Message: "amount mismatch", kind: kindAmountMismatch}
+
+// newError op(uid 대상) 호출이 실패했을 때 응답으로부터 구조화된 *Error를 만든다.
+func newError(op string, uid string, httpStatus int, code int, message string) *Error {
+	return &Error{
+		Op:         op,
+		UID:        uid,
+		Code:       code,
+		Message:    message,
+		HTTPStatus: httpStatus,
+		kind:       classifyError(httpStatus, message),
+	}
+}
+
+// classifyError HTTPStatus와 응답 메시지로부터 errors.Is로 비교 가능한 종류를 추려낸다.
+func classifyError(httpStatus int, message string) errorKind {
+	switch httpStatus {
+	case http.StatusUnauthorized:
+		return kindUnauthorized
+	case http.StatusNotFound:
+		return kindNotFound
+	}
+
+	switch {
+	case strings.Contains(message, "금액"):
+		return kindAmountMismatch
+	case strings.Contains(message, "이미 취소"):
+		return kindAlreadyCancelled
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,46 @@
+package iamport
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name       string
+		httpStatus int
+		message    string
+		want       errorKind
+	}{
+		{"unauthorized status", http.StatusUnauthorized, "무슨 메시지든", kindUnauthorized},
+		{"not found status", http.StatusNotFound, "무슨 메시지든", kindNotFound},
+		{"already cancelled", http.StatusBadRequest, "이미 취소된 거래 입니다", kindAlreadyCancelled},
+		{"amount mismatch", http.StatusBadRequest, "취소 금액이 결제 금액보다 큽니다", kindAmountMismatch},
+		{"missing cancel reason is not already-cancelled", http.StatusBadRequest, "취소 사유가 없습니다", ""},
+		{"not cancelable state is not already-cancelled", http.StatusBadRequest, "취소할 수 있는 상태가 아닙니다", ""},
+		{"unrecognized message", http.StatusBadRequest, "알 수 없는 오류", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.httpStatus, tt.message); got != tt.want {
+				t.Fatalf("classifyError(%d, %q) = %q, want %q", tt.httpStatus, tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorIsDistinguishesCancelRelatedMessages(t *testing.T) {
+	notCancelable := newError("CancelPaymentImpUID", "imp_1", http.StatusBadRequest, 0, "취소할 수 있는 상태가 아닙니다")
+
+	if errors.Is(notCancelable, ErrAlreadyCancelled) {
+		t.Fatal("a \"not in a cancelable state\" error should not be classified as ErrAlreadyCancelled")
+	}
+
+	alreadyCancelled := newError("CancelPaymentImpUID", "imp_1", http.StatusBadRequest, 0, "이미 취소된 거래 입니다")
+
+	if !errors.Is(alreadyCancelled, ErrAlreadyCancelled) {
+		t.Fatal("expected the already-cancelled message to be classified as ErrAlreadyCancelled")
+	}
+}
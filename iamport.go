@@ -2,21 +2,74 @@ package iamport
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 )
 
+// defaultBaseURL 아임포트 API의 기본 base URL이다.
+const defaultBaseURL = "https://api.iamport.kr"
+
 // Client 아임포트 서버와 통신하는 클라이언트
 type Client struct {
-	APIKey      string
-	APISecret   string
-	AccessToken accessToken
-	HTTP        *http.Client
+	APIKey    string
+	APISecret string
+	HTTP      *http.Client
+
+	baseURL   string
+	userAgent string
+
+	tokens      tokenSource
+	retry       RetryPolicy
+	idempotency sync.Map
+	idempoTTL   *time.Duration
+}
+
+// Option Client를 생성할 때 사용하는 설정 옵션
+type Option func(*Client)
+
+// WithHTTPClient Client가 사용할 *http.Client를 지정한다. 지정하지 않으면 &http.Client{}를 사용한다.
+func WithHTTPClient(h *http.Client) Option {
+	return func(cli *Client) {
+		cli.HTTP = h
+	}
+}
+
+// WithBaseURL 아임포트 API를 호출할 base URL을 지정한다. httptest.Server를 바라보게 하는 등 테스트에 사용한다.
+func WithBaseURL(baseURL string) Option {
+	return func(cli *Client) {
+		cli.baseURL = baseURL
+	}
+}
+
+// WithUserAgent 모든 요청에 실어 보낼 User-Agent 헤더를 지정한다.
+func WithUserAgent(userAgent string) Option {
+	return func(cli *Client) {
+		cli.userAgent = userAgent
+	}
+}
+
+// WithTokenRefreshBuffer AccessToken 만료 시각 이전에 미리 갱신을 시작할 기준 시간을 지정한다.
+// 지정하지 않으면 defaultTokenRefreshBuffer(60초)를 사용한다.
+func WithTokenRefreshBuffer(d time.Duration) Option {
+	return func(cli *Client) {
+		cli.tokens.refreshBuffer = d
+	}
+}
+
+// WithIdempotencyTTL doIdempotent가 캐시해 둔 응답을 재사용할 최대 기간을 지정한다.
+// 지정하지 않으면 defaultIdempotencyTTL(5분)을 사용한다. d에 0을 지정하면 캐시를
+// 완전히 비활성화한다 (매번 서버에 다시 요청한다). 이 기간이 지나면 같은 idempotency
+// key로 다시 호출했을 때도 서버에 다시 요청을 보낸다.
+func WithIdempotencyTTL(d time.Duration) Option {
+	return func(cli *Client) {
+		cli.idempoTTL = &d
+	}
 }
 
 type accessToken struct {
@@ -24,44 +77,173 @@ type accessToken struct {
 	Expired time.Time
 }
 
-// NewClient 아임포트로 부터 부여받은 API Key와 Api Secret을 사용하여 클라이언트를 새로 만든다.
-func NewClient(apiKey string, apiSecret string, cli *http.Client) *Client {
-	if cli == nil {
-		cli = &http.Client{}
+// defaultTokenRefreshBuffer authorization()이 만료 시각 이전에 미리 토큰을
+// 갱신하기 시작하는 기준 시간이다.
+const defaultTokenRefreshBuffer = 60 * time.Second
+
+// tokenSource AccessToken을 캐싱하고, 동시에 여러 goroutine이 authorization()을
+// 호출하더라도 실제 /users/getToken 요청은 한 번만 나가도록 보장한다.
+type tokenSource struct {
+	mu            sync.Mutex
+	token         accessToken
+	call          *tokenCall
+	refreshBuffer time.Duration
+}
+
+// tokenCall 진행 중인 토큰 갱신 요청 하나를 나타낸다. 뒤따라온 goroutine들은
+// 새로 요청을 보내는 대신 done이 닫히기를 기다렸다가 결과를 공유한다.
+type tokenCall struct {
+	done  chan struct{}
+	token accessToken
+	err   error
+}
+
+// get 캐시된 토큰이 충분히 유효하면 그대로 반환하고, 그렇지 않으면 fetch로
+// 갱신한다. 이미 갱신이 진행 중이라면 새 요청을 보내지 않고 그 결과를 기다린다.
+func (ts *tokenSource) get(ctx context.Context, fetch func(context.Context) (accessToken, error)) (string, error) {
+	ts.mu.Lock()
+
+	buffer := ts.refreshBuffer
+	if buffer == 0 {
+		buffer = defaultTokenRefreshBuffer
 	}
 
-	return &Client{
+	if ts.token.Token != "" && time.Until(ts.token.Expired) > buffer {
+		token := ts.token.Token
+		ts.mu.Unlock()
+		return token, nil
+	}
+
+	if call := ts.call; call != nil {
+		ts.mu.Unlock()
+		return waitForCall(ctx, call)
+	}
+
+	call := &tokenCall{done: make(chan struct{})}
+	ts.call = call
+	ts.mu.Unlock()
+
+	// fetch는 call.done을 기다리는 모든 follower를 대표해서 실행되므로, 이 goroutine을
+	// 발생시킨 caller(ctx)가 아니라 detach된 context로 실행해야 한다. 그렇지 않으면
+	// caller의 context가 취소될 때 자신의 context는 멀쩡한 다른 follower들까지 전부
+	// "context canceled"를 돌려받게 된다.
+	go func() {
+		token, err := fetch(context.Background())
+
+		ts.mu.Lock()
+		if err == nil {
+			ts.token = token
+		}
+		ts.call = nil
+		ts.mu.Unlock()
+
+		call.token = token
+		call.err = err
+		close(call.done)
+	}()
+
+	return waitForCall(ctx, call)
+}
+
+// waitForCall call이 끝나기를 기다리되, ctx가 먼저 취소되면 call 자체는 그대로 둔 채
+// 이 caller에게만 ctx.Err()를 돌려준다.
+func waitForCall(ctx context.Context, call *tokenCall) (string, error) {
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	if call.err != nil {
+		return "", call.err
+	}
+
+	return call.token.Token, nil
+}
+
+// invalidate 캐시된 토큰을 비워서 다음 get 호출이 무조건 새로 갱신하도록 만든다.
+func (ts *tokenSource) invalidate() {
+	ts.mu.Lock()
+	ts.token = accessToken{}
+	ts.mu.Unlock()
+}
+
+// NewClient 아임포트로 부터 부여받은 API Key와 Api Secret을 사용하여 클라이언트를 새로 만든다.
+func NewClient(apiKey string, apiSecret string, opts ...Option) *Client {
+	cli := &Client{
 		APIKey:    apiKey,
 		APISecret: apiSecret,
-		HTTP:      cli,
+		HTTP:      &http.Client{},
+		baseURL:   defaultBaseURL,
+	}
+
+	for _, opt := range opts {
+		opt(cli)
+	}
+
+	return cli
+}
+
+// GetToken APIKey와 APISecret을 사용하여 AccessToken을 받아 오고, 캐시를 갱신한다.
+func (cli *Client) GetToken(ctx context.Context) error {
+	token, err := cli.fetchToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	cli.tokens.mu.Lock()
+	cli.tokens.token = token
+	cli.tokens.mu.Unlock()
+
+	return nil
+}
+
+// InvalidateToken 캐시된 AccessToken을 무효화하여, 다음 API 호출이 새 토큰을
+// 받아오도록 만든다. 어떤 API 호출이 예기치 못한 401을 받았을 때 사용한다.
+func (cli *Client) InvalidateToken() {
+	cli.tokens.invalidate()
+}
+
+// setUserAgent WithUserAgent로 지정된 User-Agent 헤더를 req에 채운다.
+func (cli *Client) setUserAgent(req *http.Request) {
+	if cli.userAgent != "" {
+		req.Header.Set("User-Agent", cli.userAgent)
 	}
 }
 
-// GetToken APIKey와 APISecret을 사용하여 AccessToken을 받아 온다.
-func (cli *Client) GetToken() error {
+// fetchToken APIKey와 APISecret으로 /users/getToken을 호출하여 AccessToken을 받아온다.
+func (cli *Client) fetchToken(ctx context.Context) (accessToken, error) {
 	if cli.APIKey == "" {
-		return errors.New("iamport: APIKey is missing")
+		return accessToken{}, newError("GetToken", "", 0, 0, "APIKey is missing")
 	}
 
 	if cli.APISecret == "" {
-		return errors.New("iamport: APISecret is missing")
+		return accessToken{}, newError("GetToken", "", 0, 0, "APISecret is missing")
 	}
 
 	form := url.Values{}
 	form.Set("imp_key", cli.APIKey)
 	form.Set("imp_secret", cli.APISecret)
 
-	res, err := cli.HTTP.PostForm("https://api.iamport.kr/users/getToken", form)
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		cli.baseURL+"/users/getToken", bytes.NewBufferString(form.Encode()))
 	if err != nil {
-		return err
+		return accessToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	cli.setUserAgent(req)
+
+	res, err := cli.HTTP.Do(req)
+	if err != nil {
+		return accessToken{}, err
 	}
 
 	if res.StatusCode == http.StatusUnauthorized {
-		return errors.New("iamport: unauthorized")
+		return accessToken{}, newError("GetToken", "", res.StatusCode, 0, "unauthorized")
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return errors.New("iamport: unknown error")
+		return accessToken{}, newError("GetToken", "", res.StatusCode, 0, "unknown error")
 	}
 
 	data := struct {
@@ -76,17 +258,17 @@ func (cli *Client) GetToken() error {
 
 	err = json.NewDecoder(res.Body).Decode(&data)
 	if err != nil {
-		return err
+		return accessToken{}, err
 	}
 
 	if data.Code != 0 {
-		return fmt.Errorf("iamport: %s", data.Message)
+		return accessToken{}, newError("GetToken", "", res.StatusCode, data.Code, data.Message)
 	}
 
-	cli.AccessToken.Token = data.Response.AccessToken
-	cli.AccessToken.Expired = time.Unix(data.Response.ExpiredAt, 0)
-
-	return nil
+	return accessToken{
+		Token:   data.Response.AccessToken,
+		Expired: time.Unix(data.Response.ExpiredAt, 0),
+	}, nil
 }
 
 // Payment 결제 정보
@@ -122,59 +304,79 @@ type Payment struct {
 	ReceiptURL    string `json:"receipt_url"`
 }
 
-func (cli *Client) authorization() (string, error) {
-	now := time.Now()
+func (cli *Client) authorization(ctx context.Context) (string, error) {
+	return cli.tokens.get(ctx, cli.fetchToken)
+}
+
+// do req에 User-Agent와 Authorization 헤더를 채워 보내고, 응답이 401이면
+// 캐시된 토큰을 무효화한 뒤 새 토큰으로 한 번만 재시도한다.
+func (cli *Client) do(req *http.Request) (*http.Response, error) {
+	cli.setUserAgent(req)
+
+	auth, err := cli.authorization(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", auth)
+
+	res, err := cli.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusUnauthorized {
+		return res, nil
+	}
 
-	switch {
-	case cli.AccessToken.Token == "",
-		cli.AccessToken.Expired.IsZero(),
-		!cli.AccessToken.Expired.Before(now):
+	cli.InvalidateToken()
 
-		err := cli.GetToken()
+	if req.GetBody != nil {
+		body, err := req.GetBody()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
+		req.Body = body
+	}
+
+	auth, err = cli.authorization(req.Context())
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("Authorization", auth)
 
-	return cli.AccessToken.Token, nil
+	return cli.HTTP.Do(req)
 }
 
 // GetPaymentImpUID imp_uid로 결제 정보 가져오기
 //
 // GET /payments/{imp_uid}
-func (cli *Client) GetPaymentImpUID(iuid string) (Payment, error) {
+func (cli *Client) GetPaymentImpUID(ctx context.Context, iuid string) (Payment, error) {
 	data := struct {
 		Code     int     `json:"code"`
 		Message  string  `json:"message"`
 		Response Payment `json:"response"`
 	}{}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.iamport.kr/payments/%s", iuid), nil)
-	if err != nil {
-		return data.Response, err
-	}
-
-	auth, err := cli.authorization()
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/payments/%s", cli.baseURL, iuid), nil)
 	if err != nil {
 		return data.Response, err
 	}
-	req.Header.Set("Authorization", auth)
 
-	res, err := cli.HTTP.Do(req)
+	res, err := cli.do(req)
 	if err != nil {
 		return data.Response, err
 	}
 
 	if res.StatusCode == http.StatusUnauthorized {
-		return data.Response, errors.New("iamport: unauthorized")
+		return data.Response, newError("GetPaymentImpUID", iuid, res.StatusCode, 0, "unauthorized")
 	}
 
 	if res.StatusCode == http.StatusNotFound {
-		return data.Response, errors.New("iamport: invalid imp_uid")
+		return data.Response, newError("GetPaymentImpUID", iuid, res.StatusCode, 0, "invalid imp_uid")
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return data.Response, errors.New("iamport: unknown error")
+		return data.Response, newError("GetPaymentImpUID", iuid, res.StatusCode, 0, "unknown error")
 	}
 
 	err = json.NewDecoder(res.Body).Decode(&data)
@@ -183,7 +385,7 @@ func (cli *Client) GetPaymentImpUID(iuid string) (Payment, error) {
 	}
 
 	if data.Code != 0 {
-		return data.Response, fmt.Errorf("iamport: %s", data.Message)
+		return data.Response, newError("GetPaymentImpUID", iuid, res.StatusCode, data.Code, data.Message)
 	}
 
 	return data.Response, nil
@@ -192,40 +394,34 @@ func (cli *Client) GetPaymentImpUID(iuid string) (Payment, error) {
 // GetPaymentMerchantUID merchant_uid로 결제 정보 가져오기
 //
 // GET /payments/find/{merchant_uid}
-func (cli *Client) GetPaymentMerchantUID(muid string) (Payment, error) {
+func (cli *Client) GetPaymentMerchantUID(ctx context.Context, muid string) (Payment, error) {
 	data := struct {
 		Code     int     `json:"code"`
 		Message  string  `json:"message"`
 		Response Payment `json:"response"`
 	}{}
 
-	req, err := http.NewRequest("GET",
-		fmt.Sprintf("https://api.iamport.kr/payments/find/%s", muid), nil)
-	if err != nil {
-		return data.Response, err
-	}
-
-	auth, err := cli.authorization()
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		fmt.Sprintf("%s/payments/find/%s", cli.baseURL, muid), nil)
 	if err != nil {
 		return data.Response, err
 	}
-	req.Header.Set("Authorization", auth)
 
-	res, err := cli.HTTP.Do(req)
+	res, err := cli.do(req)
 	if err != nil {
 		return data.Response, err
 	}
 
 	if res.StatusCode == http.StatusUnauthorized {
-		return data.Response, errors.New("iamport: unauthorized")
+		return data.Response, newError("GetPaymentMerchantUID", muid, res.StatusCode, 0, "unauthorized")
 	}
 
 	if res.StatusCode == http.StatusNotFound {
-		return data.Response, errors.New("iamport: invalid merchant_uid")
+		return data.Response, newError("GetPaymentMerchantUID", muid, res.StatusCode, 0, "invalid merchant_uid")
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return data.Response, errors.New("iamport: unknown error")
+		return data.Response, newError("GetPaymentMerchantUID", muid, res.StatusCode, 0, "unknown error")
 	}
 
 	err = json.NewDecoder(res.Body).Decode(&data)
@@ -234,7 +430,7 @@ func (cli *Client) GetPaymentMerchantUID(muid string) (Payment, error) {
 	}
 
 	if data.Code != 0 {
-		return data.Response, fmt.Errorf("iamport: %s", data.Message)
+		return data.Response, newError("GetPaymentMerchantUID", muid, res.StatusCode, data.Code, data.Message)
 	}
 
 	return data.Response, nil
@@ -267,44 +463,37 @@ type PagedPayments struct {
 // GetPaymentsStatus 결제 상태에 따른 결제 정보들 가져오기
 //
 // GET /payments/status/{payment_status}
-func (cli *Client) GetPaymentsStatus(status Status, page int) (PagedPayments, error) {
+func (cli *Client) GetPaymentsStatus(ctx context.Context, status Status, page int) (PagedPayments, error) {
 	data := struct {
 		Code     int           `json:"code"`
 		Message  string        `json:"message"`
 		Response PagedPayments `json:"response"`
 	}{}
 
-	url := fmt.Sprintf("https://api.iamport.kr/payments/status/%s", status)
+	reqURL := fmt.Sprintf("%s/payments/status/%s", cli.baseURL, status)
 	if page > 0 {
-		url += fmt.Sprintf("?page=%d", page)
-	}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-
-		return data.Response, err
+		reqURL += fmt.Sprintf("?page=%d", page)
 	}
-
-	auth, err := cli.authorization()
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
 		return data.Response, err
 	}
-	req.Header.Set("Authorization", auth)
 
-	res, err := cli.HTTP.Do(req)
+	res, err := cli.do(req)
 	if err != nil {
 		return data.Response, err
 	}
 
 	if res.StatusCode == http.StatusUnauthorized {
-		return data.Response, errors.New("iamport: unauthorized")
+		return data.Response, newError("GetPaymentsStatus", string(status), res.StatusCode, 0, "unauthorized")
 	}
 
 	if res.StatusCode == http.StatusNotFound {
-		return data.Response, errors.New("iamport: invalid status or page")
+		return data.Response, newError("GetPaymentsStatus", string(status), res.StatusCode, 0, "invalid status or page")
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return data.Response, errors.New("iamport: unknown error")
+		return data.Response, newError("GetPaymentsStatus", string(status), res.StatusCode, 0, "unknown error")
 	}
 
 	err = json.NewDecoder(res.Body).Decode(&data)
@@ -313,7 +502,7 @@ func (cli *Client) GetPaymentsStatus(status Status, page int) (PagedPayments, er
 	}
 
 	if data.Code != 0 {
-		return data.Response, fmt.Errorf("iamport: %s", data.Message)
+		return data.Response, newError("GetPaymentsStatus", string(status), res.StatusCode, data.Code, data.Message)
 	}
 
 	return data.Response, nil
@@ -423,18 +612,18 @@ func (ops *CancelOptions) form() url.Values {
 // CancelPaymentImpUID imp_uid로 결제 취소하기
 //
 // GET /payments/cancel
-func (cli *Client) CancelPaymentImpUID(iuid string, options *CancelOptions) (Payment, error) {
-	return cli.cancelPayment("imp_uid", iuid, options)
+func (cli *Client) CancelPaymentImpUID(ctx context.Context, iuid string, options *CancelOptions) (Payment, error) {
+	return cli.cancelPayment(ctx, "CancelPaymentImpUID", "imp_uid", iuid, options)
 }
 
 // CancelPaymentMerchantUID merchant_uid로 결제 취소하기
 //
 // GET /payments/cancel
-func (cli *Client) CancelPaymentMerchantUID(muid string, options *CancelOptions) (Payment, error) {
-	return cli.cancelPayment("merchant_uid", muid, options)
+func (cli *Client) CancelPaymentMerchantUID(ctx context.Context, muid string, options *CancelOptions) (Payment, error) {
+	return cli.cancelPayment(ctx, "CancelPaymentMerchantUID", "merchant_uid", muid, options)
 }
 
-func (cli *Client) cancelPayment(key string, uid string, options *CancelOptions) (Payment, error) {
+func (cli *Client) cancelPayment(ctx context.Context, op string, key string, uid string, options *CancelOptions) (Payment, error) {
 	data := struct {
 		Code     int     `json:"code"`
 		Message  string  `json:"message"`
@@ -450,40 +639,38 @@ func (cli *Client) cancelPayment(key string, uid string, options *CancelOptions)
 
 	form.Set(key, uid)
 
-	req, err := http.NewRequest("POST",
-		"https://api.iamport.kr/payments/cancel",
-		bytes.NewBufferString(form.Encode()))
+	body := []byte(form.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		cli.baseURL+"/payments/cancel",
+		bytes.NewReader(body))
 	if err != nil {
 		return data.Response, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	auth, err := cli.authorization()
-	if err != nil {
-		return data.Response, err
-	}
-	req.Header.Set("Authorization", auth)
+	idempotencyKey := idempotencyKeyFromContext(ctx, "POST", "/payments/cancel", body)
 
-	res, err := cli.HTTP.Do(req)
+	status, resBody, err := cli.doIdempotent(req, idempotencyKey, body)
 	if err != nil {
 		return data.Response, err
 	}
 
-	if res.StatusCode == http.StatusUnauthorized {
-		return data.Response, errors.New("iamport: unauthorized")
+	if status == http.StatusUnauthorized {
+		return data.Response, newError(op, uid, status, 0, "unauthorized")
 	}
 
-	if res.StatusCode != http.StatusOK {
-		return data.Response, errors.New("iamport: unknown error")
+	if status != http.StatusOK {
+		return data.Response, newError(op, uid, status, 0, "unknown error")
 	}
 
-	err = json.NewDecoder(res.Body).Decode(&data)
+	err = json.Unmarshal(resBody, &data)
 	if err != nil {
 		return data.Response, err
 	}
 
 	if data.Code != 0 {
-		return data.Response, fmt.Errorf("iamport: %s", data.Message)
+		return data.Response, newError(op, uid, status, data.Code, data.Message)
 	}
 
 	return data.Response, nil
@@ -498,7 +685,7 @@ type Prepared struct {
 // PreparePayment 결제 정보 사전 등록하기
 //
 // POST /payments/prepare
-func (cli *Client) PreparePayment(muid string, amount int64) (Prepared, error) {
+func (cli *Client) PreparePayment(ctx context.Context, muid string, amount int64) (Prepared, error) {
 	data := struct {
 		Code     int      `json:"code"`
 		Message  string   `json:"message"`
@@ -509,40 +696,38 @@ func (cli *Client) PreparePayment(muid string, amount int64) (Prepared, error) {
 	form.Set("merchant_uid", muid)
 	form.Set("amount", strconv.FormatInt(amount, 10))
 
-	req, err := http.NewRequest("POST",
-		"https://api.iamport.kr/payments/prepare",
-		bytes.NewBufferString(form.Encode()))
+	body := []byte(form.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		cli.baseURL+"/payments/prepare",
+		bytes.NewReader(body))
 	if err != nil {
 		return data.Response, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	auth, err := cli.authorization()
-	if err != nil {
-		return data.Response, err
-	}
-	req.Header.Set("Authorization", auth)
+	idempotencyKey := idempotencyKeyFromContext(ctx, "POST", "/payments/prepare", body)
 
-	res, err := cli.HTTP.Do(req)
+	status, resBody, err := cli.doIdempotent(req, idempotencyKey, body)
 	if err != nil {
 		return data.Response, err
 	}
 
-	if res.StatusCode == http.StatusUnauthorized {
-		return data.Response, errors.New("iamport: unauthorized")
+	if status == http.StatusUnauthorized {
+		return data.Response, newError("PreparePayment", muid, status, 0, "unauthorized")
 	}
 
-	if res.StatusCode != http.StatusOK {
-		return data.Response, errors.New("iamport: unknown error")
+	if status != http.StatusOK {
+		return data.Response, newError("PreparePayment", muid, status, 0, "unknown error")
 	}
 
-	err = json.NewDecoder(res.Body).Decode(&data)
+	err = json.Unmarshal(resBody, &data)
 	if err != nil {
 		return data.Response, err
 	}
 
 	if data.Code != 0 {
-		return data.Response, fmt.Errorf("iamport: %s", data.Message)
+		return data.Response, newError("PreparePayment", muid, status, data.Code, data.Message)
 	}
 
 	return data.Response, nil
@@ -551,42 +736,35 @@ func (cli *Client) PreparePayment(muid string, amount int64) (Prepared, error) {
 // GetPreparedPayment 사전 등록된 결제 정보 보기
 //
 // GET /payments/prepare/{merchant_uid}
-func (cli *Client) GetPreparedPayment(muid string) (Prepared, error) {
+func (cli *Client) GetPreparedPayment(ctx context.Context, muid string) (Prepared, error) {
 	data := struct {
 		Code     int      `json:"code"`
 		Message  string   `json:"message"`
 		Response Prepared `json:"response"`
 	}{}
 
-	req, err := http.NewRequest("GET",
-		fmt.Sprintf("https://api.iamport.kr/payments/prepare/%s", muid), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		fmt.Sprintf("%s/payments/prepare/%s", cli.baseURL, muid), nil)
 	if err != nil {
 		return data.Response, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	auth, err := cli.authorization()
-	if err != nil {
-		return data.Response, err
-	}
-	req.Header.Set("Authorization", auth)
-
-	res, err := cli.HTTP.Do(req)
+	res, err := cli.do(req)
 	if err != nil {
 		return data.Response, err
 	}
 
 	if res.StatusCode == http.StatusUnauthorized {
-		return data.Response, errors.New("iamport: unauthorized")
+		return data.Response, newError("GetPreparedPayment", muid, res.StatusCode, 0, "unauthorized")
 	}
 
 	if res.StatusCode == http.StatusNotFound {
-		return data.Response, errors.New("iamport: invalid merchant_uid")
+		return data.Response, newError("GetPreparedPayment", muid, res.StatusCode, 0, "invalid merchant_uid")
 	}
 
 	if res.StatusCode != http.StatusOK {
-		fmt.Println(res.StatusCode)
-		return data.Response, errors.New("iamport: unknown error")
+		return data.Response, newError("GetPreparedPayment", muid, res.StatusCode, 0, "unknown error")
 	}
 
 	err = json.NewDecoder(res.Body).Decode(&data)
@@ -595,7 +773,7 @@ func (cli *Client) GetPreparedPayment(muid string) (Prepared, error) {
 	}
 
 	if data.Code != 0 {
-		return data.Response, fmt.Errorf("iamport: %s", data.Message)
+		return data.Response, newError("GetPreparedPayment", muid, res.StatusCode, data.Code, data.Message)
 	}
 
 	return data.Response, nil
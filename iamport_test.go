@@ -0,0 +1,81 @@
+package iamport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestClientAuthorizationSingleFlight는 여러 goroutine이 동시에 authorization을
+// 요청해도 /users/getToken이 한 번만 호출되는지, 그리고 그중 한 goroutine의
+// context가 취소되더라도 살아있는 context를 가진 다른 goroutine들이 영향을
+// 받지 않는지를 검증한다.
+func TestClientAuthorizationSingleFlight(t *testing.T) {
+	var hits int32
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/getToken", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		close(started)
+		<-release
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "",
+			"response": map[string]interface{}{
+				"access_token": "test-token",
+				"expired_at":   time.Now().Add(time.Hour).Unix(),
+				"now":          time.Now().Unix(),
+			},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cli := NewClient("key", "secret", WithBaseURL(server.URL))
+
+	winnerCtx, cancelWinner := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	results := make([]error, 100)
+
+	// winner: its context is canceled while the request is in flight.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, results[0] = cli.authorization(winnerCtx)
+	}()
+
+	<-started
+	cancelWinner()
+
+	// followers: live contexts, must not observe the winner's cancellation.
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, results[i] = cli.authorization(context.Background())
+		}(i)
+	}
+
+	close(release)
+	wg.Wait()
+
+	for i := 1; i < len(results); i++ {
+		if results[i] != nil {
+			t.Fatalf("follower %d: expected no error, got %v", i, results[i])
+		}
+	}
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("expected exactly 1 hit to /users/getToken, got %d", got)
+	}
+}
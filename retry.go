@@ -0,0 +1,183 @@
+package iamport
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy 결제 취소/사전등록처럼 상태를 변경하는 호출에 적용할 재시도 정책
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Retryable   func(*http.Response, error) bool
+}
+
+// defaultRetryPolicy 네트워크 오류와 502/503/504만 재시도하고, 4xx는 절대 재시도하지 않는다.
+var defaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+	Retryable:   defaultRetryable,
+}
+
+func defaultRetryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// WithRetryPolicy 취소/사전등록처럼 상태를 변경하는 호출에 적용할 재시도 정책을 지정한다.
+// 지정하지 않으면 defaultRetryPolicy를 사용한다.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(cli *Client) {
+		cli.retry = policy
+	}
+}
+
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey ctx에 key를 실어, 이어지는 취소/사전등록 호출이 key를
+// idempotency key로 사용하도록 만든다. 지정하지 않으면 요청 내용으로부터
+// 결정적으로 생성한 key를 사용한다.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// idempotencyKeyFromContext ctx에 WithIdempotencyKey로 지정된 key가 있으면 그것을,
+// 없으면 method+path+form의 SHA-256 해시를 idempotency key로 사용한다.
+func idempotencyKeyFromContext(ctx context.Context, method string, path string, form []byte) string {
+	if key, ok := ctx.Value(idempotencyKeyContextKey{}).(string); ok && key != "" {
+		return key
+	}
+
+	sum := sha256.Sum256(append([]byte(method+" "+path+" "), form...))
+	return hex.EncodeToString(sum[:])
+}
+
+// defaultIdempotencyTTL doIdempotent가 캐시해 둔 응답을 재사용할 기본 기간이다.
+// 이 기간을 넘기면 같은 idempotency key라도 캐시를 무시하고 다시 서버에 요청한다.
+const defaultIdempotencyTTL = 5 * time.Minute
+
+// idempotentResult idempotencyKey로 캐시해 둔, 이미 성공한 응답
+type idempotentResult struct {
+	status   int
+	body     []byte
+	storedAt time.Time
+}
+
+// doIdempotent req를 cli.retry 정책에 따라 full jitter 지수 백오프로 재시도하며 보낸다.
+// body는 재시도할 때마다 req.Body에 다시 채워 넣는다. idempotencyKey로 이미 성공한
+// 응답이 cli.idempoTTL(기본 5분) 이내에 캐시되어 있다면, 네트워크 호출 없이 그 결과를
+// 그대로 반환해서 재시도 도중의 네트워크 장애가 같은 건을 두 번 취소/등록하지 않도록
+// 한다. 캐시는 Client 수명 동안 유지되는 sync.Map이므로, TTL이 지난 항목도 같은 key로
+// 다시 조회되기 전까지는 메모리에 남아있다 — 서로 다른 idempotency key를 아주 많이
+// 생성하는 오래 사는 Client라면 메모리 사용량이 계속 늘어날 수 있다는 점에 유의한다.
+func (cli *Client) doIdempotent(req *http.Request, idempotencyKey string, body []byte) (int, []byte, error) {
+	ttl := defaultIdempotencyTTL
+	if cli.idempoTTL != nil {
+		ttl = *cli.idempoTTL
+	}
+
+	if cached, ok := cli.idempotency.Load(idempotencyKey); ok {
+		result := cached.(idempotentResult)
+		if time.Since(result.storedAt) < ttl {
+			return result.status, result.body, nil
+		}
+		cli.idempotency.Delete(idempotencyKey)
+	}
+
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	policy := cli.retry
+	if policy.MaxAttempts == 0 {
+		policy = defaultRetryPolicy
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = defaultRetryable
+	}
+
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := backoffSleep(req.Context(), policy, attempt); err != nil {
+				return 0, nil, err
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			req.ContentLength = int64(len(body))
+		}
+
+		res, err := cli.do(req)
+		if err != nil {
+			lastErr = err
+			if !retryable(nil, err) {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		resBody, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			lastErr = err
+			if !retryable(nil, err) {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		if res.StatusCode < 300 {
+			cli.idempotency.Store(idempotencyKey, idempotentResult{status: res.StatusCode, body: resBody, storedAt: time.Now()})
+		}
+
+		if !retryable(res, nil) {
+			return res.StatusCode, resBody, nil
+		}
+
+		lastErr = fmt.Errorf("iamport: retryable status %d", res.StatusCode)
+	}
+
+	return 0, nil, lastErr
+}
+
+// backoffSleep full jitter 지수 백오프: sleep = rand(0, min(MaxDelay, BaseDelay*2^(attempt-1)))
+func backoffSleep(ctx context.Context, policy RetryPolicy, attempt int) error {
+	delay := policy.BaseDelay << uint(attempt-1)
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	if delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay) + 1))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
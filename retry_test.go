@@ -0,0 +1,184 @@
+package iamport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCancelPaymentRetriesAsSingleLogicalCancel는 첫 번째 취소 시도가 502로 실패하고
+// 재시도한 두 번째 시도가 200을 응답하더라도, 실제로는 한 번만 취소가 일어난 것처럼
+// (single logical cancel) 동작하는지를 검증한다. 서버는 같은 Idempotency-Key로 온
+// 요청을 한 번의 취소로 취급한다고 가정하고, imp_uid당 취소 처리 횟수를 센다.
+func TestCancelPaymentRetriesAsSingleLogicalCancel(t *testing.T) {
+	var (
+		requests int32
+		canceled int32
+
+		mu   sync.Mutex
+		seen = map[string]bool{}
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/getToken", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "",
+			"response": map[string]interface{}{
+				"access_token": "test-token",
+				"expired_at":   time.Now().Add(time.Hour).Unix(),
+				"now":          time.Now().Unix(),
+			},
+		})
+	})
+	mux.HandleFunc("/payments/cancel", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+
+		// a request is a new cancel unless its Idempotency-Key was already seen.
+		key := r.Header.Get("Idempotency-Key")
+		mu.Lock()
+		if key == "" || !seen[key] {
+			seen[key] = true
+			atomic.AddInt32(&canceled, 1)
+		}
+		mu.Unlock()
+
+		if n == 1 {
+			// simulate a network blip on the response: the server processed the
+			// cancel, but the client sees a 502 and will retry.
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "",
+			"response": Payment{
+				ImpUID: "imp_1",
+				Status: StatusCanceled,
+			},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cli := NewClient("key", "secret", WithBaseURL(server.URL), WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	}))
+
+	ctx := WithIdempotencyKey(context.Background(), "cancel-imp_1")
+
+	payment, err := cli.CancelPaymentImpUID(ctx, "imp_1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.Status != StatusCanceled {
+		t.Fatalf("expected status %q, got %q", StatusCanceled, payment.Status)
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("expected 2 HTTP attempts (502 then 200), got %d", got)
+	}
+	if got := atomic.LoadInt32(&canceled); got != 1 {
+		t.Fatalf("expected exactly 1 logical cancel on the server, got %d", got)
+	}
+}
+
+// TestCancelPaymentIdempotencyCacheExpires는 TTL이 지나면 같은 idempotency key로
+// 다시 취소를 호출했을 때 캐시된 결과를 돌려주지 않고 서버에 다시 요청하는지를
+// 검증한다 — 별도의, 의도된 반복 호출이 영원히 막히지 않아야 한다.
+func TestCancelPaymentIdempotencyCacheExpires(t *testing.T) {
+	var requests int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/getToken", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "",
+			"response": map[string]interface{}{
+				"access_token": "test-token",
+				"expired_at":   time.Now().Add(time.Hour).Unix(),
+				"now":          time.Now().Unix(),
+			},
+		})
+	})
+	mux.HandleFunc("/payments/cancel", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":     0,
+			"message":  "",
+			"response": Payment{ImpUID: "imp_1", Status: StatusCanceled},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cli := NewClient("key", "secret", WithBaseURL(server.URL), WithIdempotencyTTL(10*time.Millisecond))
+
+	ctx := WithIdempotencyKey(context.Background(), "cancel-imp_1")
+
+	if _, err := cli.CancelPaymentImpUID(ctx, "imp_1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := cli.CancelPaymentImpUID(ctx, "imp_1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("expected the second, independent call to reach the server after the TTL expired, got %d requests", got)
+	}
+}
+
+// TestCancelPaymentIdempotencyTTLZeroDisablesCache는 WithIdempotencyTTL(0)이
+// "설정 안 함"이 아니라 "캐시를 쓰지 않음"으로 동작하는지를 검증한다.
+func TestCancelPaymentIdempotencyTTLZeroDisablesCache(t *testing.T) {
+	var requests int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/getToken", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "",
+			"response": map[string]interface{}{
+				"access_token": "test-token",
+				"expired_at":   time.Now().Add(time.Hour).Unix(),
+				"now":          time.Now().Unix(),
+			},
+		})
+	})
+	mux.HandleFunc("/payments/cancel", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":     0,
+			"message":  "",
+			"response": Payment{ImpUID: "imp_1", Status: StatusCanceled},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cli := NewClient("key", "secret", WithBaseURL(server.URL), WithIdempotencyTTL(0))
+
+	ctx := WithIdempotencyKey(context.Background(), "cancel-imp_1")
+
+	if _, err := cli.CancelPaymentImpUID(ctx, "imp_1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := cli.CancelPaymentImpUID(ctx, "imp_1", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("expected WithIdempotencyTTL(0) to disable caching entirely, got %d requests", got)
+	}
+}
@@ -0,0 +1,363 @@
+package iamport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CardInfo 빌링키 발급에 사용하는 카드 정보
+type CardInfo struct {
+	CardNumber    string
+	ExpiryYear    string
+	ExpiryMonth   string
+	BirthOrBizNum string
+	PWD2Digit     string
+}
+
+func (card CardInfo) form() url.Values {
+	vals := url.Values{}
+	vals.Set("card_number", card.CardNumber)
+	vals.Set("expiry", fmt.Sprintf("%s-%s", card.ExpiryYear, card.ExpiryMonth))
+	vals.Set("birth", card.BirthOrBizNum)
+
+	if card.PWD2Digit != "" {
+		vals.Set("pwd_2digit", card.PWD2Digit)
+	}
+
+	return vals
+}
+
+// Customer 빌링키가 발급된 구매자 정보
+type Customer struct {
+	CustomerUID      string `json:"customer_uid"`
+	CardName         string `json:"card_name"`
+	CardNumber       string `json:"card_number"`
+	CardCode         string `json:"card_code"`
+	CustomerName     string `json:"customer_name"`
+	CustomerTel      string `json:"customer_tel"`
+	CustomerEmail    string `json:"customer_email"`
+	CustomerAddr     string `json:"customer_addr"`
+	CustomerPostCode string `json:"customer_postcode"`
+	InsertedAt       int64  `json:"inserted"`
+	UpdatedAt        int64  `json:"updated"`
+}
+
+// Schedule 예약 결제할 건 하나
+type Schedule struct {
+	MerchantUID string `json:"merchant_uid"`
+	ScheduleAt  int64  `json:"schedule_at"`
+	Amount      int64  `json:"amount"`
+	Name        string `json:"name"`
+}
+
+// Scheduled 예약이 등록된 결제 건
+type Scheduled struct {
+	MerchantUID string `json:"merchant_uid"`
+	ScheduleAt  int64  `json:"schedule_at"`
+	Amount      int64  `json:"amount"`
+	Status      string `json:"status"`
+}
+
+// SubscribePayRequest PayWithBillingKey에 사용하는 비인증 결제 요청
+type SubscribePayRequest struct {
+	CustomerUID string
+	MerchantUID string
+	Amount      int64
+	Name        string
+}
+
+func (req SubscribePayRequest) form() url.Values {
+	vals := url.Values{}
+	vals.Set("customer_uid", req.CustomerUID)
+	vals.Set("merchant_uid", req.MerchantUID)
+	vals.Set("amount", strconv.FormatInt(req.Amount, 10))
+
+	if req.Name != "" {
+		vals.Set("name", req.Name)
+	}
+
+	return vals
+}
+
+// RegisterBillingKey customerUID에 card 정보로 빌링키를 발급한다.
+//
+// POST /subscribe/customers/{customer_uid}
+func (cli *Client) RegisterBillingKey(ctx context.Context, customerUID string, card CardInfo) (Customer, error) {
+	data := struct {
+		Code     int      `json:"code"`
+		Message  string   `json:"message"`
+		Response Customer `json:"response"`
+	}{}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		fmt.Sprintf("%s/subscribe/customers/%s", cli.baseURL, customerUID),
+		bytes.NewBufferString(card.form().Encode()))
+	if err != nil {
+		return data.Response, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := cli.do(req)
+	if err != nil {
+		return data.Response, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return data.Response, newError("RegisterBillingKey", customerUID, res.StatusCode, 0, "unauthorized")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return data.Response, newError("RegisterBillingKey", customerUID, res.StatusCode, 0, "unknown error")
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&data)
+	if err != nil {
+		return data.Response, err
+	}
+
+	if data.Code != 0 {
+		return data.Response, newError("RegisterBillingKey", customerUID, res.StatusCode, data.Code, data.Message)
+	}
+
+	return data.Response, nil
+}
+
+// GetCustomer customerUID로 발급된 빌링키 정보를 가져온다.
+//
+// GET /subscribe/customers/{customer_uid}
+func (cli *Client) GetCustomer(ctx context.Context, customerUID string) (Customer, error) {
+	data := struct {
+		Code     int      `json:"code"`
+		Message  string   `json:"message"`
+		Response Customer `json:"response"`
+	}{}
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		fmt.Sprintf("%s/subscribe/customers/%s", cli.baseURL, customerUID), nil)
+	if err != nil {
+		return data.Response, err
+	}
+
+	res, err := cli.do(req)
+	if err != nil {
+		return data.Response, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return data.Response, newError("GetCustomer", customerUID, res.StatusCode, 0, "unauthorized")
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return data.Response, newError("GetCustomer", customerUID, res.StatusCode, 0, "invalid customer_uid")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return data.Response, newError("GetCustomer", customerUID, res.StatusCode, 0, "unknown error")
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&data)
+	if err != nil {
+		return data.Response, err
+	}
+
+	if data.Code != 0 {
+		return data.Response, newError("GetCustomer", customerUID, res.StatusCode, data.Code, data.Message)
+	}
+
+	return data.Response, nil
+}
+
+// DeleteCustomer customerUID로 발급된 빌링키를 삭제한다.
+//
+// DELETE /subscribe/customers/{customer_uid}
+func (cli *Client) DeleteCustomer(ctx context.Context, customerUID string) error {
+	data := struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}{}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE",
+		fmt.Sprintf("%s/subscribe/customers/%s", cli.baseURL, customerUID), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := cli.do(req)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return newError("DeleteCustomer", customerUID, res.StatusCode, 0, "unauthorized")
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return newError("DeleteCustomer", customerUID, res.StatusCode, 0, "invalid customer_uid")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return newError("DeleteCustomer", customerUID, res.StatusCode, 0, "unknown error")
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&data)
+	if err != nil {
+		return err
+	}
+
+	if data.Code != 0 {
+		return newError("DeleteCustomer", customerUID, res.StatusCode, data.Code, data.Message)
+	}
+
+	return nil
+}
+
+// PayWithBillingKey req.CustomerUID에 발급된 빌링키로 비인증 결제를 실행한다.
+//
+// POST /subscribe/payments/again
+func (cli *Client) PayWithBillingKey(ctx context.Context, req SubscribePayRequest) (Payment, error) {
+	data := struct {
+		Code     int     `json:"code"`
+		Message  string  `json:"message"`
+		Response Payment `json:"response"`
+	}{}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST",
+		cli.baseURL+"/subscribe/payments/again",
+		bytes.NewBufferString(req.form().Encode()))
+	if err != nil {
+		return data.Response, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := cli.do(httpReq)
+	if err != nil {
+		return data.Response, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return data.Response, newError("PayWithBillingKey", req.CustomerUID, res.StatusCode, 0, "unauthorized")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return data.Response, newError("PayWithBillingKey", req.CustomerUID, res.StatusCode, 0, "unknown error")
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&data)
+	if err != nil {
+		return data.Response, err
+	}
+
+	if data.Code != 0 {
+		return data.Response, newError("PayWithBillingKey", req.CustomerUID, res.StatusCode, data.Code, data.Message)
+	}
+
+	return data.Response, nil
+}
+
+// SchedulePayments customerUID에 발급된 빌링키로 schedules에 담긴 결제 건들을 예약한다.
+//
+// POST /subscribe/payments/schedule
+func (cli *Client) SchedulePayments(ctx context.Context, customerUID string, schedules []Schedule) ([]Scheduled, error) {
+	data := struct {
+		Code     int         `json:"code"`
+		Message  string      `json:"message"`
+		Response []Scheduled `json:"response"`
+	}{}
+
+	encoded, err := json.Marshal(schedules)
+	if err != nil {
+		return data.Response, err
+	}
+
+	form := url.Values{}
+	form.Set("customer_uid", customerUID)
+	form.Set("schedules", string(encoded))
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		cli.baseURL+"/subscribe/payments/schedule",
+		bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return data.Response, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := cli.do(req)
+	if err != nil {
+		return data.Response, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return data.Response, newError("SchedulePayments", customerUID, res.StatusCode, 0, "unauthorized")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return data.Response, newError("SchedulePayments", customerUID, res.StatusCode, 0, "unknown error")
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&data)
+	if err != nil {
+		return data.Response, err
+	}
+
+	if data.Code != 0 {
+		return data.Response, newError("SchedulePayments", customerUID, res.StatusCode, data.Code, data.Message)
+	}
+
+	return data.Response, nil
+}
+
+// UnschedulePayments customerUID에 예약된 결제 건들 중 merchantUIDs에 해당하는 예약을 취소한다.
+//
+// POST /subscribe/payments/unschedule
+func (cli *Client) UnschedulePayments(ctx context.Context, customerUID string, merchantUIDs []string) error {
+	data := struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}{}
+
+	encoded, err := json.Marshal(merchantUIDs)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("customer_uid", customerUID)
+	form.Set("merchant_uid", string(encoded))
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		cli.baseURL+"/subscribe/payments/unschedule",
+		bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := cli.do(req)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return newError("UnschedulePayments", customerUID, res.StatusCode, 0, "unauthorized")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return newError("UnschedulePayments", customerUID, res.StatusCode, 0, "unknown error")
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&data)
+	if err != nil {
+		return err
+	}
+
+	if data.Code != 0 {
+		return newError("UnschedulePayments", customerUID, res.StatusCode, data.Code, data.Message)
+	}
+
+	return nil
+}
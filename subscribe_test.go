@@ -0,0 +1,157 @@
+package iamport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newSubscribeTestServer(t *testing.T, customerUID string, customer Customer) (*httptest.Server, *Client) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/getToken", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "",
+			"response": map[string]interface{}{
+				"access_token": "test-token",
+				"expired_at":   time.Now().Add(time.Hour).Unix(),
+				"now":          time.Now().Unix(),
+			},
+		})
+	})
+	mux.HandleFunc("/subscribe/customers/"+customerUID, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"code":     0,
+				"message":  "",
+				"response": customer,
+			})
+		case http.MethodDelete:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"code":    0,
+				"message": "",
+			})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/subscribe/payments/again", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "",
+			"response": Payment{
+				ImpUID:      "imp_1",
+				MerchantUID: r.FormValue("merchant_uid"),
+				Status:      StatusPaid,
+			},
+		})
+	})
+	mux.HandleFunc("/subscribe/payments/schedule", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "",
+			"response": []Scheduled{
+				{MerchantUID: "order-1", Amount: 1000, Status: "scheduled"},
+			},
+		})
+	})
+	mux.HandleFunc("/subscribe/payments/unschedule", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "",
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	return server, NewClient("key", "secret", WithBaseURL(server.URL))
+}
+
+func TestRegisterBillingKey(t *testing.T) {
+	server, cli := newSubscribeTestServer(t, "cus_1", Customer{CustomerUID: "cus_1", CardName: "국민카드"})
+	defer server.Close()
+
+	customer, err := cli.RegisterBillingKey(context.Background(), "cus_1", CardInfo{
+		CardNumber:    "1234-5678-1234-5678",
+		ExpiryYear:    "25",
+		ExpiryMonth:   "12",
+		BirthOrBizNum: "801010",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if customer.CustomerUID != "cus_1" {
+		t.Fatalf("expected customer_uid cus_1, got %q", customer.CustomerUID)
+	}
+}
+
+func TestGetCustomer(t *testing.T) {
+	server, cli := newSubscribeTestServer(t, "cus_1", Customer{CustomerUID: "cus_1", CardName: "국민카드"})
+	defer server.Close()
+
+	customer, err := cli.GetCustomer(context.Background(), "cus_1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if customer.CardName != "국민카드" {
+		t.Fatalf("expected card_name 국민카드, got %q", customer.CardName)
+	}
+}
+
+func TestDeleteCustomer(t *testing.T) {
+	server, cli := newSubscribeTestServer(t, "cus_1", Customer{CustomerUID: "cus_1"})
+	defer server.Close()
+
+	if err := cli.DeleteCustomer(context.Background(), "cus_1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPayWithBillingKey(t *testing.T) {
+	server, cli := newSubscribeTestServer(t, "cus_1", Customer{CustomerUID: "cus_1"})
+	defer server.Close()
+
+	payment, err := cli.PayWithBillingKey(context.Background(), SubscribePayRequest{
+		CustomerUID: "cus_1",
+		MerchantUID: "order-1",
+		Amount:      1000,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payment.MerchantUID != "order-1" {
+		t.Fatalf("expected merchant_uid order-1, got %q", payment.MerchantUID)
+	}
+	if payment.Status != StatusPaid {
+		t.Fatalf("expected status %q, got %q", StatusPaid, payment.Status)
+	}
+}
+
+func TestSchedulePayments(t *testing.T) {
+	server, cli := newSubscribeTestServer(t, "cus_1", Customer{CustomerUID: "cus_1"})
+	defer server.Close()
+
+	scheduled, err := cli.SchedulePayments(context.Background(), "cus_1", []Schedule{
+		{MerchantUID: "order-1", ScheduleAt: time.Now().Add(24 * time.Hour).Unix(), Amount: 1000},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scheduled) != 1 || scheduled[0].MerchantUID != "order-1" {
+		t.Fatalf("unexpected scheduled payments: %+v", scheduled)
+	}
+}
+
+func TestUnschedulePayments(t *testing.T) {
+	server, cli := newSubscribeTestServer(t, "cus_1", Customer{CustomerUID: "cus_1"})
+	defer server.Close()
+
+	if err := cli.UnschedulePayments(context.Background(), "cus_1", []string{"order-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
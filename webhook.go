@@ -0,0 +1,223 @@
+package iamport
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+// WebhookHandler 아임포트가 가맹점 서버로 전송하는 결제 알림(webhook)을 받아
+// 발신지를 검증하고 등록된 콜백으로 전달하는 http.Handler
+type WebhookHandler struct {
+	cli *Client
+
+	allowedNets []*net.IPNet
+	hmacHeader  string
+	hmacSecret  []byte
+
+	onPaid        func(context.Context, Payment) error
+	onCanceled    func(context.Context, Payment) error
+	onFailed      func(context.Context, Payment) error
+	onVBankIssued func(context.Context, Payment) error
+}
+
+// WebhookOption WebhookHandler를 생성할 때 사용하는 설정 옵션
+type WebhookOption func(*WebhookHandler)
+
+// WithAllowedIPs 주어진 IP 또는 CIDR 목록에서 온 요청만 허용하도록 설정한다.
+func WithAllowedIPs(ips ...string) WebhookOption {
+	return func(h *WebhookHandler) {
+		for _, ip := range ips {
+			if _, ipnet, err := net.ParseCIDR(ip); err == nil {
+				h.allowedNets = append(h.allowedNets, ipnet)
+				continue
+			}
+
+			parsed := net.ParseIP(ip)
+			if parsed == nil {
+				continue
+			}
+
+			bits := 32
+			if parsed.To4() == nil {
+				bits = 128
+			}
+			h.allowedNets = append(h.allowedNets, &net.IPNet{IP: parsed, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+}
+
+// WithHMACSecret header 헤더에 담긴 hex 인코딩된 HMAC-SHA256 서명을 secret으로 검증하도록 설정한다.
+func WithHMACSecret(header string, secret string) WebhookOption {
+	return func(h *WebhookHandler) {
+		h.hmacHeader = header
+		h.hmacSecret = []byte(secret)
+	}
+}
+
+// NewWebhookHandler cli를 사용하여 webhook 알림을 검증하고 콜백을 호출하는 핸들러를 만든다.
+func NewWebhookHandler(cli *Client, opts ...WebhookOption) *WebhookHandler {
+	h := &WebhookHandler{cli: cli}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// OnPaid 결제 완료(paid) 알림을 받았을 때 호출할 콜백을 등록한다.
+func (h *WebhookHandler) OnPaid(fn func(context.Context, Payment) error) {
+	h.onPaid = fn
+}
+
+// OnCanceled 결제 취소(canceled) 알림을 받았을 때 호출할 콜백을 등록한다.
+func (h *WebhookHandler) OnCanceled(fn func(context.Context, Payment) error) {
+	h.onCanceled = fn
+}
+
+// OnFailed 결제 실패(failed) 알림을 받았을 때 호출할 콜백을 등록한다.
+func (h *WebhookHandler) OnFailed(fn func(context.Context, Payment) error) {
+	h.onFailed = fn
+}
+
+// OnVBankIssued 가상계좌가 발급되었을 때 호출할 콜백을 등록한다.
+func (h *WebhookHandler) OnVBankIssued(fn func(context.Context, Payment) error) {
+	h.onVBankIssued = fn
+}
+
+// notification 아임포트가 POST로 전송하는 webhook 알림 payload. amount는 아임포트가
+// 보내는 기본 payload에는 없고 일부 연동에서만 실려 오므로, 실려 왔을 때만 검증할 수
+// 있도록 포인터로 둔다.
+type notification struct {
+	ImpUID      string `json:"imp_uid"`
+	MerchantUID string `json:"merchant_uid"`
+	Amount      *int64 `json:"amount"`
+	Status      string `json:"status"`
+}
+
+// ServeHTTP notification을 파싱하고 발신지와 서명을 검증한 뒤, GetPaymentImpUID로
+// 실제 결제 정보를 다시 조회해 notification의 status(그리고 amount가 실려 왔다면
+// amount도)가 일치하는지 확인하고 (trust but verify) 등록된 콜백을 호출한다. 콜백이
+// nil을 반환해야만 200을 응답하며, 그 외에는 아임포트가 재시도할 수 있도록 4xx/5xx를
+// 응답한다. 해당 status에 등록된 콜백이 없으면 아무것도 호출하지 않고 200을 응답한다.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.allowedSource(r) {
+		http.Error(w, "iamport: source ip not allowed", http.StatusForbidden)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "iamport: failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.verifySignature(r, body) {
+		http.Error(w, "iamport: invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var note notification
+	if err := json.Unmarshal(body, &note); err != nil {
+		http.Error(w, "iamport: invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if note.ImpUID == "" {
+		http.Error(w, "iamport: missing imp_uid", http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.cli.GetPaymentImpUID(r.Context(), note.ImpUID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("iamport: failed to verify payment: %s", err), http.StatusBadGateway)
+		return
+	}
+
+	if payment.Status != note.Status {
+		http.Error(w, "iamport: notification does not match payment status", http.StatusConflict)
+		return
+	}
+
+	if note.Amount != nil && payment.Amount != *note.Amount {
+		http.Error(w, "iamport: notification does not match payment amount", http.StatusConflict)
+		return
+	}
+
+	cb := h.callbackFor(payment)
+	if cb == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := cb(r.Context(), payment); err != nil {
+		http.Error(w, fmt.Sprintf("iamport: callback failed: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *WebhookHandler) callbackFor(payment Payment) func(context.Context, Payment) error {
+	switch {
+	case payment.Status == StatusPaid:
+		return h.onPaid
+	case payment.Status == StatusCanceled:
+		return h.onCanceled
+	case payment.Status == StatusFailed:
+		return h.onFailed
+	case payment.Status == StatusReady && payment.VBankNum != "":
+		return h.onVBankIssued
+	default:
+		return nil
+	}
+}
+
+func (h *WebhookHandler) allowedSource(r *http.Request) bool {
+	if len(h.allowedNets) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipnet := range h.allowedNets {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *WebhookHandler) verifySignature(r *http.Request, body []byte) bool {
+	if h.hmacHeader == "" {
+		return true
+	}
+
+	sig := r.Header.Get(h.hmacHeader)
+	if sig == "" {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, h.hmacSecret)
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return subtle.ConstantTimeCompare([]byte(sig), []byte(expected)) == 1
+}
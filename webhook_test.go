@@ -0,0 +1,153 @@
+package iamport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestImportServer(t *testing.T, payment Payment) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/getToken", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "",
+			"response": map[string]interface{}{
+				"access_token": "test-token",
+				"expired_at":   9999999999,
+				"now":          0,
+			},
+		})
+	})
+	mux.HandleFunc("/payments/"+payment.ImpUID, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":     0,
+			"message":  "",
+			"response": payment,
+		})
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestWebhookHandlerRejectsDisallowedSource(t *testing.T) {
+	server := newTestImportServer(t, Payment{ImpUID: "imp_1", Status: StatusPaid, Amount: 1000})
+	defer server.Close()
+
+	cli := NewClient("key", "secret", WithBaseURL(server.URL))
+	handler := NewWebhookHandler(cli, WithAllowedIPs("10.0.0.0/8"))
+
+	body, _ := json.Marshal(notification{ImpUID: "imp_1", Status: StatusPaid, Amount: int64Ptr(1000)})
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(body))
+	req.RemoteAddr = "203.0.113.1:12345"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+}
+
+func TestWebhookHandlerRejectsInvalidSignature(t *testing.T) {
+	server := newTestImportServer(t, Payment{ImpUID: "imp_1", Status: StatusPaid, Amount: 1000})
+	defer server.Close()
+
+	cli := NewClient("key", "secret", WithBaseURL(server.URL))
+	handler := NewWebhookHandler(cli, WithHMACSecret("X-Iamport-Signature", "shared-secret"))
+
+	body, _ := json.Marshal(notification{ImpUID: "imp_1", Status: StatusPaid, Amount: int64Ptr(1000)})
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(body))
+	req.Header.Set("X-Iamport-Signature", "not-the-right-signature")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+}
+
+func TestWebhookHandlerRejectsAmountMismatch(t *testing.T) {
+	server := newTestImportServer(t, Payment{ImpUID: "imp_1", Status: StatusPaid, Amount: 1000})
+	defer server.Close()
+
+	cli := NewClient("key", "secret", WithBaseURL(server.URL))
+	handler := NewWebhookHandler(cli)
+	handler.OnPaid(func(ctx context.Context, p Payment) error { return nil })
+
+	// tampered notification claims a different amount than the real payment
+	body, _ := json.Marshal(notification{ImpUID: "imp_1", Status: StatusPaid, Amount: int64Ptr(1)})
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on amount mismatch, got %d", rec.Code)
+	}
+}
+
+func TestWebhookHandlerDispatchesOnMatch(t *testing.T) {
+	server := newTestImportServer(t, Payment{ImpUID: "imp_1", Status: StatusPaid, Amount: 1000})
+	defer server.Close()
+
+	cli := NewClient("key", "secret", WithBaseURL(server.URL))
+	handler := NewWebhookHandler(cli)
+
+	var called bool
+	handler.OnPaid(func(ctx context.Context, p Payment) error {
+		called = true
+		return nil
+	})
+
+	body, _ := json.Marshal(notification{ImpUID: "imp_1", Status: StatusPaid, Amount: int64Ptr(1000)})
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !called {
+		t.Fatal("expected OnPaid callback to be called")
+	}
+}
+
+// TestWebhookHandlerAcceptsPayloadWithoutAmount는 아임포트의 기본 webhook payload처럼
+// amount 필드가 아예 없는 notification도 정상적으로 처리하는지 검증한다 — amount는
+// 검증할 수 있을 때만 검증해야 하며, 없다고 해서 거부해서는 안 된다.
+func TestWebhookHandlerAcceptsPayloadWithoutAmount(t *testing.T) {
+	server := newTestImportServer(t, Payment{ImpUID: "imp_1", Status: StatusPaid, Amount: 1000})
+	defer server.Close()
+
+	cli := NewClient("key", "secret", WithBaseURL(server.URL))
+	handler := NewWebhookHandler(cli)
+
+	var called bool
+	handler.OnPaid(func(ctx context.Context, p Payment) error {
+		called = true
+		return nil
+	})
+
+	body := []byte(`{"imp_uid":"imp_1","merchant_uid":"order-1","status":"paid"}`)
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !called {
+		t.Fatal("expected OnPaid callback to be called")
+	}
+}